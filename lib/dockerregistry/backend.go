@@ -0,0 +1,46 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+// RegistryBackend identifies which transport ReadDigestsAndTags (and the
+// rest of the promotion pipeline) should use to talk to a given registry.
+type RegistryBackend int
+
+const (
+	// GCloudBackend shells out to "gcloud container images ...". It only
+	// works against GCR, and is kept as the default for backward
+	// compatibility.
+	GCloudBackend RegistryBackend = iota
+	// HTTPBackend talks to the registry directly over the Docker Registry
+	// HTTP API V2 (see lib/registry), and works against any
+	// OCI-conformant registry (GCR, Harbor, Quay, ACR, GHCR, self-hosted
+	// distribution, ...).
+	HTTPBackend
+)
+
+// BackendForRegistry returns which RegistryBackend should be used to talk to
+// name, according to backends. Registries absent from backends default to
+// GCloudBackend, so that manifests written before the HTTP backend existed
+// keep working unchanged.
+func BackendForRegistry(
+	name RegistryName, backends map[RegistryName]RegistryBackend) RegistryBackend {
+
+	if backend, ok := backends[name]; ok {
+		return backend
+	}
+	return GCloudBackend
+}
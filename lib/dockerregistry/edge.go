@@ -0,0 +1,151 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import "fmt"
+
+// PromotionEdge is the most granular unit of work considered by the
+// promotion planner: it represents copying a single source image (addressed
+// by registry, tag, and digest) to a single destination image (addressed by
+// registry and tag). A full promotion is just a set of these edges.
+type PromotionEdge struct {
+	SrcRegistry RegistryName
+	SrcImageTag ImageTag
+	SrcDigest   Digest
+
+	DstRegistry RegistryName
+	DstImageTag ImageTag
+}
+
+// PQIN (registry + image name + tag) identifies a single mutable reference
+// in a destination registry. Two edges that share a PQIN both want to write
+// the same tag, and so must agree on the digest they are writing.
+type PQIN struct {
+	Registry  RegistryName
+	ImageName ImageName
+	Tag       Tag
+}
+
+// ToPromotionEdges takes a list of Manifests and fans each one out into the
+// PromotionEdges it implies: for every image/digest/tag in a manifest, one
+// edge is generated for each of the manifest's registries that is not marked
+// as the source.
+func ToPromotionEdges(mfests []Manifest) map[PromotionEdge]interface{} {
+	edges := make(map[PromotionEdge]interface{})
+
+	for _, mfest := range mfests {
+		var src RegistryContext
+		for _, rc := range mfest.Registries {
+			if rc.Src {
+				src = rc
+				break
+			}
+		}
+
+		for _, image := range mfest.Images {
+			for digest, tagArray := range image.Dmap {
+				// A digest with no tags is still promoted, as a single
+				// tagless edge addressable only by "image@digest" in the
+				// destination (see ClassifyPromotionRequests).
+				tags := tagArray
+				if len(tags) == 0 {
+					tags = TagSlice{""}
+				}
+
+				for _, tag := range tags {
+					for _, rc := range mfest.Registries {
+						if rc.Name == src.Name {
+							continue
+						}
+						edge := PromotionEdge{
+							SrcRegistry: src.Name,
+							SrcImageTag: ImageTag{ImageName: image.ImageName, Tag: tag},
+							SrcDigest:   digest,
+
+							DstRegistry: rc.Name,
+							DstImageTag: ImageTag{
+								ImageName: mfest.imageNameFor(image.ImageName, rc.Name),
+								Tag:       tag,
+							},
+						}
+						edges[edge] = nil
+					}
+				}
+			}
+		}
+	}
+
+	return edges
+}
+
+// CheckOverlappingEdges groups edges by destination PQIN. It is not an error
+// for more than one manifest to promote to the same PQIN, as long as they
+// all agree on the digest being promoted there; such duplicate edges are
+// benign redundancy and are merged away. It is an error for two edges to
+// disagree on the digest for the same destination PQIN, because there is no
+// way to satisfy both by writing a single tag. All such conflicting
+// destinations are collected and returned together, instead of failing on
+// the first one found.
+//
+// A tagless edge (DstImageTag.Tag == "") is not addressed by a mutable tag
+// at all --- it promotes "image@digest", which is inherently immutable ---
+// so two tagless edges with different digests are never a conflict, even if
+// they share a destination registry and image name. Such edges are passed
+// straight through, deduplicated only where two edges are identical in every
+// field (source included), via the edges map itself.
+func CheckOverlappingEdges(
+	edges map[PromotionEdge]interface{}) (map[PromotionEdge]interface{}, error) {
+
+	filtered := make(map[PromotionEdge]interface{})
+	digestForPQIN := make(map[PQIN]Digest)
+	var conflicts []PQIN
+	seenConflict := make(map[PQIN]interface{})
+
+	for edge := range edges {
+		if edge.DstImageTag.Tag == "" {
+			filtered[edge] = nil
+			continue
+		}
+
+		pqin := PQIN{
+			Registry:  edge.DstRegistry,
+			ImageName: edge.DstImageTag.ImageName,
+			Tag:       edge.DstImageTag.Tag,
+		}
+
+		if existingDigest, ok := digestForPQIN[pqin]; ok {
+			if existingDigest != edge.SrcDigest {
+				if _, already := seenConflict[pqin]; !already {
+					conflicts = append(conflicts, pqin)
+					seenConflict[pqin] = nil
+				}
+			}
+			continue
+		}
+
+		digestForPQIN[pqin] = edge.SrcDigest
+		filtered[edge] = nil
+	}
+
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf(
+			"more than one digest wants to be promoted to the following destination(s): %v",
+			conflicts)
+	}
+
+	return filtered, nil
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import "testing"
+
+func TestCheckOverlappingEdges(t *testing.T) {
+	tests := []struct {
+		name         string
+		edges        map[PromotionEdge]interface{}
+		wantErr      bool
+		wantFiltered int
+	}{
+		{
+			name: "identical digests at the same destination are deduped, not an error",
+			edges: map[PromotionEdge]interface{}{
+				{DstRegistry: "dest", DstImageTag: ImageTag{ImageName: "foo", Tag: "v1"}, SrcDigest: "sha256:aaa"}: nil,
+				{DstRegistry: "dest", DstImageTag: ImageTag{ImageName: "foo", Tag: "v1"}, SrcDigest: "sha256:aaa"}: nil,
+			},
+			wantErr:      false,
+			wantFiltered: 1,
+		},
+		{
+			name: "different digests at the same tagged destination is a conflict",
+			edges: map[PromotionEdge]interface{}{
+				{DstRegistry: "dest", DstImageTag: ImageTag{ImageName: "foo", Tag: "v1"}, SrcDigest: "sha256:aaa"}: nil,
+				{DstRegistry: "dest", DstImageTag: ImageTag{ImageName: "foo", Tag: "v1"}, SrcDigest: "sha256:bbb"}: nil,
+			},
+			wantErr: true,
+		},
+		{
+			name: "different digests under tagless (empty-tag) entries are never a conflict",
+			edges: map[PromotionEdge]interface{}{
+				{DstRegistry: "dest", DstImageTag: ImageTag{ImageName: "base", Tag: ""}, SrcDigest: "sha256:aaa"}: nil,
+				{DstRegistry: "dest", DstImageTag: ImageTag{ImageName: "base", Tag: ""}, SrcDigest: "sha256:bbb"}: nil,
+			},
+			wantErr:      false,
+			wantFiltered: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filtered, err := CheckOverlappingEdges(test.edges)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("CheckOverlappingEdges() = _, <nil>, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CheckOverlappingEdges() = _, %v, want no error", err)
+			}
+			if len(filtered) != test.wantFiltered {
+				t.Errorf("len(filtered) = %d, want %d", len(filtered), test.wantFiltered)
+			}
+		})
+	}
+}
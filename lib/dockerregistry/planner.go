@@ -0,0 +1,119 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+// ClassifyPromotionRequests compares the desired state of a destination
+// registry's image (want) against its actual current state (have) and
+// produces the PromotionRequests needed to reconcile them.
+//
+// An Add is generated for a (digest, tag) pair in want that isn't in have.
+// A Move is generated for a tag that is in both, but points at a different
+// digest in have than in want (it can be thought of as a Delete immediately
+// followed by an Add). A Delete is generated for a tag that is in have but
+// no longer wanted.
+//
+// A tagless entry in want (a digest mapped to an empty TagSlice, see
+// ToPromotionEdges) only ever produces an Add, and only if the digest isn't
+// already present in have under some tag: there is no tag to move or
+// delete, so the only thing to reconcile is whether the digest-addressable
+// manifest exists at all in the destination.
+func ClassifyPromotionRequests(
+	dest RegistryName, imageName ImageName, want, have DigestTags) []PromotionRequest {
+
+	var reqs []PromotionRequest
+
+	haveDigestForTag := make(map[Tag]Digest)
+	for digest, tags := range have {
+		for _, tag := range tags {
+			haveDigestForTag[tag] = digest
+		}
+	}
+
+	for digest, tags := range want {
+		if len(tags) == 0 {
+			if _, ok := have[digest]; !ok {
+				reqs = append(reqs, PromotionRequest{
+					TagOp:      Add,
+					Registries: RegistryNames{Dest: dest},
+					ImageName:  imageName,
+					Digest:     digest,
+				})
+			}
+			continue
+		}
+
+		for _, tag := range tags {
+			haveDigest, tagExists := haveDigestForTag[tag]
+			switch {
+			case !tagExists:
+				reqs = append(reqs, PromotionRequest{
+					TagOp:      Add,
+					Registries: RegistryNames{Dest: dest},
+					ImageName:  imageName,
+					Digest:     digest,
+					Tag:        tag,
+				})
+			case haveDigest != digest:
+				reqs = append(reqs, PromotionRequest{
+					TagOp:      Move,
+					Registries: RegistryNames{Dest: dest},
+					ImageName:  imageName,
+					Digest:     digest,
+					DigestOld:  haveDigest,
+					Tag:        tag,
+				})
+			}
+		}
+	}
+
+	for tag, haveDigest := range haveDigestForTag {
+		if _, stillWanted := wantDigestForTag(want, tag); !stillWanted {
+			reqs = append(reqs, PromotionRequest{
+				TagOp:      Delete,
+				Registries: RegistryNames{Dest: dest},
+				ImageName:  imageName,
+				Digest:     haveDigest,
+				Tag:        tag,
+			})
+		}
+	}
+
+	return reqs
+}
+
+func wantDigestForTag(want DigestTags, tag Tag) (Digest, bool) {
+	for digest, tags := range want {
+		for _, t := range tags {
+			if t == tag {
+				return digest, true
+			}
+		}
+	}
+	return "", false
+}
+
+// RefForRequest returns the manifest reference a registry backend should
+// operate on for req: the tag, if req is a tag-moving request, or otherwise
+// the digest itself, for a tagless promotion (req.Tag == ""). This is what
+// gets substituted into "gcrane cp src@... dst@<ref>" or
+// "PUT /v2/<name>/manifests/<ref>", depending on backend.
+func RefForRequest(req PromotionRequest) string {
+	if req.Tag != "" {
+		return string(req.Tag)
+	}
+	return string(req.Digest)
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+// EdgesToDigestTags regroups a (filtered) edge set by destination registry
+// and image name, producing the per-(registry, image) DigestTags view that
+// ClassifyPromotionRequests expects as its "want" argument.
+func EdgesToDigestTags(edges map[PromotionEdge]interface{}) map[RegistryName]map[ImageName]DigestTags {
+	want := make(map[RegistryName]map[ImageName]DigestTags)
+
+	for edge := range edges {
+		byImage, ok := want[edge.DstRegistry]
+		if !ok {
+			byImage = make(map[ImageName]DigestTags)
+			want[edge.DstRegistry] = byImage
+		}
+
+		digestTags, ok := byImage[edge.DstImageTag.ImageName]
+		if !ok {
+			digestTags = make(DigestTags)
+			byImage[edge.DstImageTag.ImageName] = digestTags
+		}
+
+		if edge.DstImageTag.Tag == "" {
+			if _, ok := digestTags[edge.SrcDigest]; !ok {
+				digestTags[edge.SrcDigest] = TagSlice{}
+			}
+			continue
+		}
+
+		digestTags[edge.SrcDigest] = append(digestTags[edge.SrcDigest], edge.DstImageTag.Tag)
+	}
+
+	return want
+}
+
+// PlanPromotions runs the full planning pipeline: it fans mfests out into
+// PromotionEdges, resolves benign overlaps and fails on genuine conflicts
+// (CheckOverlappingEdges), and classifies the surviving edges against the
+// actual state of each destination registry (have) into the Add/Move/Delete
+// PromotionRequests needed to reconcile them.
+func PlanPromotions(mfests []Manifest, have MasterInventory) ([]PromotionRequest, error) {
+	edges := ToPromotionEdges(mfests)
+
+	filtered, err := CheckOverlappingEdges(edges)
+	if err != nil {
+		return nil, err
+	}
+
+	want := EdgesToDigestTags(filtered)
+
+	var reqs []PromotionRequest
+	for destRegistry, byImage := range want {
+		for imageName, wantDigestTags := range byImage {
+			haveDigestTags := have[destRegistry][imageName]
+			reqs = append(
+				reqs, ClassifyPromotionRequests(destRegistry, imageName, wantDigestTags, haveDigestTags)...)
+		}
+	}
+
+	return reqs, nil
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import "testing"
+
+func TestPlanPromotions(t *testing.T) {
+	mfests := []Manifest{
+		{
+			Registries: []RegistryContext{
+				{Name: "src", Src: true},
+				{Name: "dest"},
+			},
+			Images: []Image{
+				{
+					ImageName: "foo",
+					Dmap: DigestTags{
+						"sha256:aaa": {"v1"},
+					},
+				},
+			},
+		},
+	}
+
+	have := MasterInventory{
+		"dest": RegInvImage{
+			"foo": DigestTags{
+				"sha256:old": {"v1"},
+			},
+		},
+	}
+
+	reqs, err := PlanPromotions(mfests, have)
+	if err != nil {
+		t.Fatalf("PlanPromotions() = _, %v, want no error", err)
+	}
+	if len(reqs) != 1 {
+		t.Fatalf("len(reqs) = %d, want 1 (a single Move); got %+v", len(reqs), reqs)
+	}
+	if reqs[0].TagOp != Move {
+		t.Errorf("reqs[0].TagOp = %v, want Move", reqs[0].TagOp)
+	}
+}
+
+func TestPlanPromotionsRejectsConflicts(t *testing.T) {
+	mfests := []Manifest{
+		{
+			Registries: []RegistryContext{
+				{Name: "src1", Src: true},
+				{Name: "dest"},
+			},
+			Images: []Image{
+				{ImageName: "foo", Dmap: DigestTags{"sha256:aaa": {"v1"}}},
+			},
+		},
+		{
+			Registries: []RegistryContext{
+				{Name: "src2", Src: true},
+				{Name: "dest"},
+			},
+			Images: []Image{
+				{ImageName: "foo", Dmap: DigestTags{"sha256:bbb": {"v1"}}},
+			},
+		},
+	}
+
+	if _, err := PlanPromotions(mfests, MasterInventory{}); err == nil {
+		t.Fatal("PlanPromotions() = _, <nil>, want an error for conflicting digests")
+	}
+}
@@ -0,0 +1,224 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxReferenceLength is the total length limit for an image reference
+// string, matching the limit enforced by the Docker/OCI reference grammar.
+const maxReferenceLength = 255
+
+var (
+	domainComponentRegexp = regexp.MustCompile(
+		`^(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9])$`)
+	pathComponentRegexp = regexp.MustCompile(
+		`^[a-z0-9]+(?:(?:[._]|__|[-]+)[a-z0-9]+)*$`)
+	tagRegexp = regexp.MustCompile(
+		`^[\w][\w.-]{0,127}$`)
+	digestAlgorithmRegexp = regexp.MustCompile(`^[A-Za-z0-9]+(?:[.+_-][A-Za-z0-9]+)*$`)
+	hexRegexp             = regexp.MustCompile(`^[0-9a-fA-F]+$`)
+)
+
+// digestHexLength is both the set of digest algorithms the promoter knows
+// how to handle, and the exact hex-encoded length each one's digest must
+// be. Anything else is rejected even if it is otherwise well-formed,
+// because we have no way to verify it.
+var digestHexLength = map[string]int{
+	"sha256": 64,
+	"sha384": 96,
+	"sha512": 128,
+}
+
+// Reference is a fully parsed image reference, as a user would type it on a
+// "docker pull" command line: a registry domain, a repository path, and
+// optionally a tag and/or a digest.
+type Reference struct {
+	Registry RegistryName
+	Image    ImageName
+	Tag      Tag
+	Digest   Digest
+}
+
+// String returns the canonical, fully-qualified form of r:
+// "registry/image[:tag][@digest]".
+func (r Reference) String() string {
+	s := string(r.Registry) + "/" + string(r.Image)
+	if r.Tag != "" {
+		s += ":" + string(r.Tag)
+	}
+	if r.Digest != "" {
+		s += "@" + string(r.Digest)
+	}
+	return s
+}
+
+// Familiar returns a shortened form of r suitable for display to a human:
+// it prefers the tag over the digest, since a tag is what someone skimming
+// logs actually recognizes, and only falls back to the digest when there is
+// no tag.
+func (r Reference) Familiar() string {
+	s := string(r.Registry) + "/" + string(r.Image)
+	switch {
+	case r.Tag != "":
+		s += ":" + string(r.Tag)
+	case r.Digest != "":
+		s += "@" + string(r.Digest)
+	}
+	return s
+}
+
+// ParseReference parses s as a Docker/OCI image reference --- of the form
+// "name[:tag][@digest]", where name is "[domain/]path-component(/path-component)*"
+// --- and splits it into its constituent fields. It is strict: malformed
+// input is rejected here, before it can reach a network call.
+func ParseReference(s string) (RegistryName, ImageName, Tag, Digest, error) {
+	ref, err := ParseReferenceStruct(s)
+	if err != nil {
+		return "", "", "", "", err
+	}
+	return ref.Registry, ref.Image, ref.Tag, ref.Digest, nil
+}
+
+// ParseReferenceStruct is like ParseReference, but returns a Reference.
+func ParseReferenceStruct(s string) (Reference, error) {
+	if len(s) == 0 {
+		return Reference{}, fmt.Errorf("image reference is empty")
+	}
+	if len(s) > maxReferenceLength {
+		return Reference{}, fmt.Errorf(
+			"image reference %q is %d characters, longer than the %d-character limit",
+			s, len(s), maxReferenceLength)
+	}
+
+	rest := s
+
+	var digest Digest
+	if i := strings.Index(rest, "@"); i >= 0 {
+		digestPart := rest[i+1:]
+		if err := validateDigest(digestPart); err != nil {
+			return Reference{}, fmt.Errorf("%q: %v", s, err)
+		}
+		digest = Digest(digestPart)
+		rest = rest[:i]
+	}
+
+	var tag Tag
+	lastSlash := strings.LastIndex(rest, "/")
+	if lastColon := strings.LastIndex(rest, ":"); lastColon > lastSlash {
+		tagPart := rest[lastColon+1:]
+		if !tagRegexp.MatchString(tagPart) {
+			return Reference{}, fmt.Errorf("%q: invalid tag %q", s, tagPart)
+		}
+		tag = Tag(tagPart)
+		rest = rest[:lastColon]
+	}
+
+	registry, image, err := splitDomain(rest)
+	if err != nil {
+		return Reference{}, fmt.Errorf("%q: %v", s, err)
+	}
+
+	return Reference{
+		Registry: registry,
+		Image:    image,
+		Tag:      tag,
+		Digest:   digest,
+	}, nil
+}
+
+// splitDomain splits "domain/path-component(/path-component)*" into its
+// RegistryName and ImageName, validating both along the way. A domain is
+// required: the promoter always talks to a specific registry, so there is
+// no "default registry" to fall back to.
+func splitDomain(s string) (RegistryName, ImageName, error) {
+	i := strings.Index(s, "/")
+	if i < 0 {
+		return "", "", fmt.Errorf("missing registry domain in %q", s)
+	}
+	domain, path := s[:i], s[i+1:]
+
+	// Per Docker's reference grammar, the leading component is only a
+	// domain if it contains a "." or ":", or is exactly "localhost";
+	// otherwise there is no domain at all, and the whole string is just a
+	// repository path (which this package always rejects, since a
+	// promotion always needs an explicit registry to talk to).
+	if !strings.ContainsAny(domain, ".:") && domain != "localhost" {
+		return "", "", fmt.Errorf("missing registry domain in %q", s)
+	}
+
+	if err := validateDomain(domain); err != nil {
+		return "", "", err
+	}
+	if path == "" {
+		return "", "", fmt.Errorf("missing image path after domain %q", domain)
+	}
+	for _, component := range strings.Split(path, "/") {
+		if !pathComponentRegexp.MatchString(component) {
+			return "", "", fmt.Errorf("invalid path component %q in %q", component, path)
+		}
+	}
+
+	return RegistryName(domain), ImageName(path), nil
+}
+
+func validateDomain(domain string) error {
+	host := domain
+	if i := strings.LastIndex(domain, ":"); i >= 0 {
+		host = domain[:i]
+		if _, err := strconv.Atoi(domain[i+1:]); err != nil {
+			return fmt.Errorf("invalid port in domain %q", domain)
+		}
+	}
+
+	if host == "localhost" {
+		return nil
+	}
+
+	for _, component := range strings.Split(host, ".") {
+		if !domainComponentRegexp.MatchString(component) {
+			return fmt.Errorf("invalid domain %q", domain)
+		}
+	}
+	return nil
+}
+
+func validateDigest(s string) error {
+	i := strings.Index(s, ":")
+	if i < 0 {
+		return fmt.Errorf("malformed digest %q", s)
+	}
+	algo, hex := s[:i], s[i+1:]
+	if !digestAlgorithmRegexp.MatchString(algo) {
+		return fmt.Errorf("malformed digest %q", s)
+	}
+
+	wantLen, ok := digestHexLength[strings.ToLower(algo)]
+	if !ok {
+		return fmt.Errorf("unsupported digest algorithm %q", algo)
+	}
+	if len(hex) != wantLen || !hexRegexp.MatchString(hex) {
+		return fmt.Errorf(
+			"malformed digest %q: %s digests must be exactly %d hex characters, got %d",
+			s, algo, wantLen, len(hex))
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseReference(t *testing.T) {
+	sha256hex := strings.Repeat("a", 64)
+	sha384hex := strings.Repeat("a", 96)
+
+	tests := []struct {
+		name       string
+		ref        string
+		wantErr    bool
+		wantRegTag RegistryName
+		wantImage  ImageName
+		wantTag    Tag
+		wantDigest Digest
+	}{
+		{
+			name:       "tag only",
+			ref:        "gcr.io/foo/bar:v1",
+			wantRegTag: "gcr.io",
+			wantImage:  "foo/bar",
+			wantTag:    "v1",
+		},
+		{
+			name:       "tag and digest",
+			ref:        "gcr.io/foo/bar:v1@sha256:" + sha256hex,
+			wantRegTag: "gcr.io",
+			wantImage:  "foo/bar",
+			wantTag:    "v1",
+			wantDigest: Digest("sha256:" + sha256hex),
+		},
+		{
+			name:       "digest only, other supported algorithm",
+			ref:        "registry.example.com:5000/foo@sha384:" + sha384hex,
+			wantRegTag: "registry.example.com:5000",
+			wantImage:  "foo",
+			wantDigest: Digest("sha384:" + sha384hex),
+		},
+		{
+			name:       "localhost domain",
+			ref:        "localhost/foo:v1",
+			wantRegTag: "localhost",
+			wantImage:  "foo",
+			wantTag:    "v1",
+		},
+		{
+			name:    "no domain is rejected even though it parses as name/tag",
+			ref:     "foo/bar:v1",
+			wantErr: true,
+		},
+		{
+			name:    "digest too short for its algorithm is rejected",
+			ref:     "gcr.io/foo/bar@sha256:" + strings.Repeat("a", 40),
+			wantErr: true,
+		},
+		{
+			name:    "digest too long for its algorithm is rejected",
+			ref:     "gcr.io/foo/bar@sha256:" + strings.Repeat("a", 65),
+			wantErr: true,
+		},
+		{
+			name:    "unsupported digest algorithm is rejected",
+			ref:     "gcr.io/foo/bar@md5:" + strings.Repeat("a", 32),
+			wantErr: true,
+		},
+		{
+			name:    "invalid tag character is rejected",
+			ref:     "gcr.io/foo/bar:v1!",
+			wantErr: true,
+		},
+		{
+			name:    "empty reference is rejected",
+			ref:     "",
+			wantErr: true,
+		},
+		{
+			name:    "over-length reference is rejected",
+			ref:     "gcr.io/" + strings.Repeat("a", 300) + ":v1",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reg, image, tag, digest, err := ParseReference(test.ref)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("ParseReference(%q) = _, _, _, _, <nil>, want an error", test.ref)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseReference(%q) = _, _, _, _, %v, want no error", test.ref, err)
+			}
+			if reg != test.wantRegTag || image != test.wantImage || tag != test.wantTag || digest != test.wantDigest {
+				t.Errorf(
+					"ParseReference(%q) = %q, %q, %q, %q, want %q, %q, %q, %q",
+					test.ref, reg, image, tag, digest,
+					test.wantRegTag, test.wantImage, test.wantTag, test.wantDigest)
+			}
+		})
+	}
+}
+
+func TestReferenceFamiliar(t *testing.T) {
+	r := Reference{Registry: "gcr.io", Image: "foo/bar", Tag: "v1", Digest: "sha256:deadbeef"}
+	if got, want := r.Familiar(), "gcr.io/foo/bar:v1"; got != want {
+		t.Errorf("Familiar() = %q, want %q", got, want)
+	}
+
+	r.Tag = ""
+	if got, want := r.Familiar(), "gcr.io/foo/bar@sha256:deadbeef"; got != want {
+		t.Errorf("Familiar() = %q, want %q", got, want)
+	}
+}
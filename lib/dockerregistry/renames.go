@@ -0,0 +1,101 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import "fmt"
+
+// RegistryImagePath identifies an image's repository path within a specific
+// registry, e.g. {Registry: "gcr.io/staging", ImageName: "addon-builder"}.
+// It is the unit used by Manifest.Renames to say "this image lives at this
+// path in this registry".
+type RegistryImagePath struct {
+	Registry  RegistryName
+	ImageName ImageName
+}
+
+// Validate checks m for internal consistency and, on success, populates
+// m.renamesDenormalized so that ToRegInvImageDigest/ToRegInvImageTag and
+// ToPromotionEdges can resolve renamed destination paths. It must be called
+// before any of those methods are used on a Manifest that has Renames set.
+func (m *Manifest) Validate() error {
+	registered := make(map[RegistryName]interface{})
+	for _, rc := range m.Registries {
+		registered[rc.Name] = nil
+	}
+
+	// The only registry we have any local knowledge of is the source
+	// registry, whose contents are exactly m.Images: there is no network
+	// call here to ask a destination registry whether a path exists in
+	// it, so that is the one existence check we can actually make.
+	knownImages := make(map[ImageName]interface{})
+	for _, image := range m.Images {
+		knownImages[image.ImageName] = nil
+	}
+
+	denormalized := make(map[RegistryImagePath]map[RegistryName]ImageName)
+	seenDst := make(map[RegistryImagePath]interface{})
+
+	for _, group := range m.Renames {
+		var src RegistryImagePath
+		haveSrc := false
+
+		for _, path := range group {
+			if _, ok := registered[path.Registry]; !ok {
+				return fmt.Errorf(
+					"rename group %v: registry %q is not one of this manifest's registries",
+					group, path.Registry)
+			}
+			if path.Registry == m.srcRegistry() {
+				if _, ok := knownImages[path.ImageName]; !ok {
+					return fmt.Errorf(
+						"rename group %v: image %q does not exist in the source registry %q",
+						group, path.ImageName, path.Registry)
+				}
+				src = path
+				haveSrc = true
+			}
+		}
+
+		if !haveSrc {
+			return fmt.Errorf(
+				"rename group %v: no entry for the source registry %q", group, m.srcRegistry())
+		}
+
+		if _, dup := denormalized[src]; dup {
+			return fmt.Errorf(
+				"source path %+v is claimed by more than one rename group", src)
+		}
+
+		byDest := make(map[RegistryName]ImageName)
+		for _, path := range group {
+			if path.Registry == src.Registry {
+				continue
+			}
+			if _, dup := seenDst[path]; dup {
+				return fmt.Errorf(
+					"destination path %+v is claimed by more than one rename group", path)
+			}
+			seenDst[path] = nil
+			byDest[path.Registry] = path.ImageName
+		}
+
+		denormalized[src] = byDest
+	}
+
+	m.renamesDenormalized = denormalized
+	return nil
+}
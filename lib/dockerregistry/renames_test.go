@@ -0,0 +1,116 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import "testing"
+
+func baseRenameManifest() Manifest {
+	return Manifest{
+		Registries: []RegistryContext{
+			{Name: "src", Src: true},
+			{Name: "dest"},
+		},
+		Images: []Image{
+			{ImageName: "addon-builder", Dmap: DigestTags{"sha256:aaa": {"v1"}}},
+		},
+	}
+}
+
+func TestManifestValidateRenames(t *testing.T) {
+	t.Run("valid rename resolves through the denormalized index", func(t *testing.T) {
+		m := baseRenameManifest()
+		m.Renames = [][]RegistryImagePath{
+			{
+				{Registry: "src", ImageName: "addon-builder"},
+				{Registry: "dest", ImageName: "addons/addon-builder"},
+			},
+		}
+
+		if err := m.Validate(); err != nil {
+			t.Fatalf("Validate() = %v, want no error", err)
+		}
+
+		if got, want := m.imageNameFor("addon-builder", "dest"), ImageName("addons/addon-builder"); got != want {
+			t.Errorf("imageNameFor() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("rename naming an image missing from the source is rejected", func(t *testing.T) {
+		m := baseRenameManifest()
+		m.Renames = [][]RegistryImagePath{
+			{
+				{Registry: "src", ImageName: "does-not-exist"},
+				{Registry: "dest", ImageName: "renamed"},
+			},
+		}
+
+		if err := m.Validate(); err == nil {
+			t.Fatal("Validate() = <nil>, want an error for a nonexistent source image")
+		}
+	})
+
+	t.Run("two groups sharing a source path is rejected", func(t *testing.T) {
+		m := baseRenameManifest()
+		m.Renames = [][]RegistryImagePath{
+			{
+				{Registry: "src", ImageName: "addon-builder"},
+				{Registry: "dest", ImageName: "renamed-a"},
+			},
+			{
+				{Registry: "src", ImageName: "addon-builder"},
+				{Registry: "dest", ImageName: "renamed-b"},
+			},
+		}
+
+		if err := m.Validate(); err == nil {
+			t.Fatal("Validate() = <nil>, want an error for a duplicate source path")
+		}
+	})
+
+	t.Run("two groups colliding on a destination path is rejected", func(t *testing.T) {
+		m := baseRenameManifest()
+		m.Images = append(m.Images, Image{ImageName: "sidecar", Dmap: DigestTags{"sha256:bbb": {"v1"}}})
+		m.Renames = [][]RegistryImagePath{
+			{
+				{Registry: "src", ImageName: "addon-builder"},
+				{Registry: "dest", ImageName: "shared"},
+			},
+			{
+				{Registry: "src", ImageName: "sidecar"},
+				{Registry: "dest", ImageName: "shared"},
+			},
+		}
+
+		if err := m.Validate(); err == nil {
+			t.Fatal("Validate() = <nil>, want an error for a duplicate destination path")
+		}
+	})
+
+	t.Run("unregistered registry in a rename group is rejected", func(t *testing.T) {
+		m := baseRenameManifest()
+		m.Renames = [][]RegistryImagePath{
+			{
+				{Registry: "src", ImageName: "addon-builder"},
+				{Registry: "not-a-manifest-registry", ImageName: "renamed"},
+			},
+		}
+
+		if err := m.Validate(); err == nil {
+			t.Fatal("Validate() = <nil>, want an error for an unregistered registry")
+		}
+	})
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"context"
+	"fmt"
+)
+
+// Severity is how bad a single scan Finding is. Severities are ordered
+// least to most severe (see severityRank) so they can be compared against a
+// configured threshold.
+type Severity string
+
+// The severities a Scanner can report, ordered least to most severe.
+const (
+	SeverityLow      Severity = "LOW"
+	SeverityMedium   Severity = "MEDIUM"
+	SeverityHigh     Severity = "HIGH"
+	SeverityCritical Severity = "CRITICAL"
+)
+
+var severityRank = map[Severity]int{
+	SeverityLow:      1,
+	SeverityMedium:   2,
+	SeverityHigh:     3,
+	SeverityCritical: 4,
+}
+
+// AtLeast reports whether s is at least as severe as threshold. s == ""
+// means "no finding" and is never at least as severe as anything, even an
+// empty threshold. An empty (unconfigured) threshold defaults to
+// SeverityCritical, the highest threshold, so that a ScannerConfig with no
+// Threshold set doesn't accidentally block on every finding.
+func (s Severity) AtLeast(threshold Severity) bool {
+	if s == "" {
+		return false
+	}
+	if threshold == "" {
+		threshold = SeverityCritical
+	}
+	return severityRank[s] >= severityRank[threshold]
+}
+
+// HigherSeverity returns whichever of a and b is more severe, with an
+// unrecognized or empty Severity ranking below every known one. Unlike
+// AtLeast, it treats "" as "no opinion" rather than "defaults to critical",
+// which makes it the right tool for folding a set of Findings down to a
+// single overall ScanResult.Severity: the fold starts at "", and must stay
+// there if every finding is somehow unranked rather than jumping to
+// SeverityCritical.
+func HigherSeverity(a, b Severity) Severity {
+	if severityRank[b] > severityRank[a] {
+		return b
+	}
+	return a
+}
+
+// Finding is a single thing a Scanner turned up about an image, e.g. one
+// CVE, or one script assertion that failed.
+type Finding struct {
+	Severity    Severity
+	Description string
+	Metadata    map[string]string
+}
+
+// ScanResult is everything a Scanner learned about one image. Severity is
+// the highest severity among Findings (or "" if there were none).
+type ScanResult struct {
+	Severity Severity
+	Findings []Finding
+	Metadata map[string]string
+}
+
+// Scanner inspects the content of an image and reports what it finds.
+// Registered scanners are run on every candidate PromotionEdge after
+// planning but before execution (see RunScanners), so a promotion can be
+// gated on what's actually inside the image rather than just its name and
+// digest.
+type Scanner interface {
+	Scan(ctx context.Context, registry RegistryName, image ImageName, digest Digest) (ScanResult, error)
+}
+
+// ScanPolicy controls what RunScanners does when a Scanner reports a
+// finding at or above its configured threshold.
+type ScanPolicy string
+
+const (
+	// ScanPolicyFail drops the offending edge and fails the promotion
+	// outright. This is the default if ScannerConfig.Policy is empty.
+	ScanPolicyFail ScanPolicy = "fail"
+	// ScanPolicyWarn still promotes the edge, but surfaces the finding to
+	// the caller as a warning instead of failing the promotion.
+	ScanPolicyWarn ScanPolicy = "warn"
+)
+
+// ScannerConfig configures one Scanner to run as part of a manifest's
+// promotion. It lives in Manifest.Scanners so that scanning policy travels
+// with the promotion spec, rather than being set separately per-invocation
+// and easy to forget.
+type ScannerConfig struct {
+	Name      string     `yaml:"name"`
+	Threshold Severity   `yaml:"threshold"`
+	Policy    ScanPolicy `yaml:"policy,omitempty"`
+}
+
+// policy returns c's configured policy, defaulting to ScanPolicyFail.
+func (c ScannerConfig) policy() ScanPolicy {
+	if c.Policy == "" {
+		return ScanPolicyFail
+	}
+	return c.Policy
+}
+
+// RunScanners scans every candidate edge with each of the manifest's
+// configured scanners (looked up from registry by ScannerConfig.Name), and
+// returns the edges that are clear to promote.
+//
+// If a finding is at or above its ScannerConfig's Threshold: under
+// ScanPolicyWarn the edge is still cleared, but the result is added to the
+// returned warnings; under ScanPolicyFail (the default) RunScanners returns
+// an error and no edges are cleared, since a partially-gated promotion
+// would be more confusing than a promotion that simply didn't happen.
+func RunScanners(
+	ctx context.Context,
+	edges map[PromotionEdge]interface{},
+	registry map[string]Scanner,
+	configs []ScannerConfig) (map[PromotionEdge]interface{}, []ScanResult, error) {
+
+	cleared := make(map[PromotionEdge]interface{})
+	var warnings []ScanResult
+
+	for edge := range edges {
+		for _, cfg := range configs {
+			scanner, ok := registry[cfg.Name]
+			if !ok {
+				return nil, nil, fmt.Errorf("no registered scanner named %q", cfg.Name)
+			}
+
+			result, err := scanner.Scan(ctx, edge.SrcRegistry, edge.SrcImageTag.ImageName, edge.SrcDigest)
+			if err != nil {
+				return nil, nil, fmt.Errorf(
+					"scanning %s with %q: %v", edge.SrcImageTag.ImageName, cfg.Name, err)
+			}
+
+			if !result.Severity.AtLeast(cfg.Threshold) {
+				continue
+			}
+
+			if cfg.policy() == ScanPolicyWarn {
+				warnings = append(warnings, result)
+				continue
+			}
+
+			return nil, nil, fmt.Errorf(
+				"promotion of %s blocked: scanner %q reported severity %q (threshold %q)",
+				edge.SrcImageTag.ImageName, cfg.Name, result.Severity, cfg.Threshold)
+		}
+
+		cleared[edge] = nil
+	}
+
+	return cleared, warnings, nil
+}
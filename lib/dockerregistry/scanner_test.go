@@ -0,0 +1,137 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSeverityAtLeast(t *testing.T) {
+	tests := []struct {
+		name      string
+		severity  Severity
+		threshold Severity
+		want      bool
+	}{
+		{name: "no finding never triggers, even with an empty threshold", severity: "", threshold: "", want: false},
+		{name: "no finding never triggers a concrete threshold", severity: "", threshold: SeverityLow, want: false},
+		{name: "empty threshold defaults to requiring critical", severity: SeverityHigh, threshold: "", want: false},
+		{name: "empty threshold is met by a critical finding", severity: SeverityCritical, threshold: "", want: true},
+		{name: "equal severity meets the threshold", severity: SeverityHigh, threshold: SeverityHigh, want: true},
+		{name: "lower severity does not meet a higher threshold", severity: SeverityLow, threshold: SeverityHigh, want: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.severity.AtLeast(test.threshold); got != test.want {
+				t.Errorf("%q.AtLeast(%q) = %v, want %v", test.severity, test.threshold, got, test.want)
+			}
+		})
+	}
+}
+
+func TestHigherSeverity(t *testing.T) {
+	tests := []struct {
+		name string
+		a    Severity
+		b    Severity
+		want Severity
+	}{
+		{name: "higher second argument wins", a: SeverityLow, b: SeverityHigh, want: SeverityHigh},
+		{name: "higher first argument wins", a: SeverityCritical, b: SeverityMedium, want: SeverityCritical},
+		{name: "empty accumulator is replaced by any ranked severity", a: "", b: SeverityLow, want: SeverityLow},
+		{name: "empty accumulator stays empty against another empty", a: "", b: "", want: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := HigherSeverity(test.a, test.b); got != test.want {
+				t.Errorf("HigherSeverity(%q, %q) = %q, want %q", test.a, test.b, got, test.want)
+			}
+		})
+	}
+}
+
+// cleanScanner reports no findings at all.
+type cleanScanner struct{}
+
+func (cleanScanner) Scan(ctx context.Context, registry RegistryName, image ImageName, digest Digest) (ScanResult, error) {
+	return ScanResult{}, nil
+}
+
+// dirtyScanner always reports a single finding at a fixed severity.
+type dirtyScanner struct {
+	severity Severity
+}
+
+func (s dirtyScanner) Scan(ctx context.Context, registry RegistryName, image ImageName, digest Digest) (ScanResult, error) {
+	return ScanResult{
+		Severity: s.severity,
+		Findings: []Finding{{Severity: s.severity, Description: "fake finding"}},
+	}, nil
+}
+
+func TestRunScannersCleanImageWithUnsetThreshold(t *testing.T) {
+	edges := map[PromotionEdge]interface{}{
+		{SrcRegistry: "src", SrcImageTag: ImageTag{ImageName: "foo", Tag: "v1"}}: nil,
+	}
+	registry := map[string]Scanner{"clean": cleanScanner{}}
+	configs := []ScannerConfig{{Name: "clean"}}
+
+	cleared, warnings, err := RunScanners(context.Background(), edges, registry, configs)
+	if err != nil {
+		t.Fatalf("RunScanners() = _, _, %v, want no error for a clean scan", err)
+	}
+	if len(cleared) != 1 {
+		t.Errorf("len(cleared) = %d, want 1", len(cleared))
+	}
+	if len(warnings) != 0 {
+		t.Errorf("len(warnings) = %d, want 0", len(warnings))
+	}
+}
+
+func TestRunScannersBlocksAboveThreshold(t *testing.T) {
+	edges := map[PromotionEdge]interface{}{
+		{SrcRegistry: "src", SrcImageTag: ImageTag{ImageName: "foo", Tag: "v1"}}: nil,
+	}
+	registry := map[string]Scanner{"dirty": dirtyScanner{severity: SeverityCritical}}
+	configs := []ScannerConfig{{Name: "dirty", Threshold: SeverityHigh}}
+
+	if _, _, err := RunScanners(context.Background(), edges, registry, configs); err == nil {
+		t.Fatal("RunScanners() = _, _, <nil>, want an error for a finding above threshold")
+	}
+}
+
+func TestRunScannersWarnPolicyStillClears(t *testing.T) {
+	edges := map[PromotionEdge]interface{}{
+		{SrcRegistry: "src", SrcImageTag: ImageTag{ImageName: "foo", Tag: "v1"}}: nil,
+	}
+	registry := map[string]Scanner{"dirty": dirtyScanner{severity: SeverityCritical}}
+	configs := []ScannerConfig{{Name: "dirty", Threshold: SeverityHigh, Policy: ScanPolicyWarn}}
+
+	cleared, warnings, err := RunScanners(context.Background(), edges, registry, configs)
+	if err != nil {
+		t.Fatalf("RunScanners() = _, _, %v, want no error under ScanPolicyWarn", err)
+	}
+	if len(cleared) != 1 {
+		t.Errorf("len(cleared) = %d, want 1", len(cleared))
+	}
+	if len(warnings) != 1 {
+		t.Errorf("len(warnings) = %d, want 1", len(warnings))
+	}
+}
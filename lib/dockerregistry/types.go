@@ -130,9 +130,25 @@ type PromotionRequest struct {
 // Manifest stores the information in a manifest file (describing the
 // desired state of a Docker Registry).
 type Manifest struct {
-	Registries     RegistryNames
-	ServiceAccount string  `yaml:"service-account,omitempty"`
-	Images         []Image `yaml:"images,omitempty"`
+	Registries []RegistryContext     `yaml:"registries"`
+	Images     []Image               `yaml:"images,omitempty"`
+	Renames    [][]RegistryImagePath `yaml:"renames,omitempty"`
+	Scanners   []ScannerConfig       `yaml:"scanners,omitempty"`
+
+	// renamesDenormalized indexes Renames for fast lookup during
+	// promotion planning; it is populated by Validate() and is nil on a
+	// freshly-unmarshaled Manifest. See renames.go.
+	renamesDenormalized map[RegistryImagePath]map[RegistryName]ImageName
+}
+
+// RegistryContext holds information about a single registry that
+// participates in a promotion: its name, the service account to use when
+// talking to it, and whether it is the source registry for the manifest (as
+// opposed to one of the possibly-many destination registries).
+type RegistryContext struct {
+	Name           RegistryName `yaml:"name"`
+	ServiceAccount string       `yaml:"service-account,omitempty"`
+	Src            bool         `yaml:"src,omitempty"`
 }
 
 // Image holds information about an image. It's like an "Object" in the OOP
@@ -207,13 +223,40 @@ type ProcessRequest func(
 
 // Various conversion functions.
 
-// ToRegInvImageDigest converts a Manifest to a RegInvImageDigest.
-func (m Manifest) ToRegInvImageDigest() RegInvImageDigest {
+// srcRegistry returns the name of the registry marked as the source in
+// m.Registries, or the zero value if none is marked.
+func (m Manifest) srcRegistry() RegistryName {
+	for _, rc := range m.Registries {
+		if rc.Src {
+			return rc.Name
+		}
+	}
+	return ""
+}
+
+// imageNameFor returns the ImageName that srcImage should be promoted under
+// in destRegistry: the renamed name if a rename applies (see renames.go),
+// or srcImage's own name otherwise.
+func (m Manifest) imageNameFor(srcImage ImageName, destRegistry RegistryName) ImageName {
+	byDest, ok := m.renamesDenormalized[RegistryImagePath{Registry: m.srcRegistry(), ImageName: srcImage}]
+	if !ok {
+		return srcImage
+	}
+	if renamed, ok := byDest[destRegistry]; ok {
+		return renamed
+	}
+	return srcImage
+}
+
+// ToRegInvImageDigest converts a Manifest to a RegInvImageDigest, as the
+// desired state of destRegistry (taking any applicable rename into
+// account).
+func (m Manifest) ToRegInvImageDigest(destRegistry RegistryName) RegInvImageDigest {
 	riid := make(RegInvImageDigest)
 	for _, image := range m.Images {
 		for digest, tagArray := range image.Dmap {
 			id := ImageDigest{}
-			id.ImageName = image.ImageName
+			id.ImageName = m.imageNameFor(image.ImageName, destRegistry)
 			id.Digest = digest
 			riid[id] = tagArray
 		}
@@ -221,14 +264,15 @@ func (m Manifest) ToRegInvImageDigest() RegInvImageDigest {
 	return riid
 }
 
-// ToRegInvImageTag converts a Manifest to a RegInvImageTag.
-func (m Manifest) ToRegInvImageTag() RegInvImageTag {
+// ToRegInvImageTag converts a Manifest to a RegInvImageTag, as the desired
+// state of destRegistry (taking any applicable rename into account).
+func (m Manifest) ToRegInvImageTag(destRegistry RegistryName) RegInvImageTag {
 	riit := make(RegInvImageTag)
 	for _, image := range m.Images {
 		for digest, tagArray := range image.Dmap {
 			for _, tag := range tagArray {
 				it := ImageTag{}
-				it.ImageName = image.ImageName
+				it.ImageName = m.imageNameFor(image.ImageName, destRegistry)
 				it.Tag = tag
 				riit[it] = digest
 			}
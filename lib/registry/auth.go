@@ -0,0 +1,258 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// AuthResolver produces the Authorization header value to use for a request
+// against a given registry host and repository. It is consulted lazily, only
+// after a request comes back 401 with a WWW-Authenticate challenge, so that a
+// single AuthResolver can serve every repository on a registry without
+// needing to know its scopes up front.
+type AuthResolver interface {
+	// Authorize returns the value to set as the "Authorization" header,
+	// given the WWW-Authenticate challenge the registry responded with.
+	Authorize(ctx context.Context, challenge string) (string, error)
+}
+
+// authTransport wraps an http.RoundTripper so that any request which
+// receives a 401 Unauthorized is retried once with credentials obtained from
+// resolver, per the challenge in the response's WWW-Authenticate header.
+type authTransport struct {
+	host     string
+	resolver AuthResolver
+	base     http.RoundTripper
+}
+
+func (t *authTransport) baseTransport() http.RoundTripper {
+	if t.base != nil {
+		return t.base
+	}
+	return http.DefaultTransport
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.baseTransport().RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || t.resolver == nil {
+		return resp, err
+	}
+
+	challenge := resp.Header.Get("WWW-Authenticate")
+	if challenge == "" {
+		return resp, nil
+	}
+
+	authz, authzErr := t.resolver.Authorize(req.Context(), challenge)
+	if authzErr != nil {
+		return nil, fmt.Errorf("authenticating to %q: %v", t.host, authzErr)
+	}
+
+	resp.Body.Close()
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		// req.Body was already consumed by the first, unauthenticated
+		// attempt above; without rewinding it here, the retry would send
+		// an empty body and every write against a bearer/basic-challenging
+		// registry (e.g. PutManifest) would fail with a Content-Length
+		// mismatch.
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("rewinding request body to retry against %q: %v", t.host, err)
+		}
+		retry.Body = body
+	}
+	retry.Header.Set("Authorization", authz)
+	return t.baseTransport().RoundTrip(retry)
+}
+
+// BasicAuthResolver authenticates with a static username and password,
+// ignoring the challenge (used by registries that only support Basic auth,
+// e.g. some self-hosted distribution instances).
+type BasicAuthResolver struct {
+	Username string
+	Password string
+}
+
+// Authorize implements AuthResolver.
+func (r *BasicAuthResolver) Authorize(ctx context.Context, challenge string) (string, error) {
+	req := &http.Request{Header: http.Header{}}
+	req.SetBasicAuth(r.Username, r.Password)
+	return req.Header.Get("Authorization"), nil
+}
+
+// TokenSource returns a bearer token for the given realm/service/scope, as
+// parsed out of a "Bearer" WWW-Authenticate challenge. Implementations
+// include exchanging a GCP service account key for an access token, or
+// hitting a registry's own token endpoint with a refresh token.
+type TokenSource interface {
+	Token(ctx context.Context, realm, service, scope string) (string, error)
+}
+
+// BearerAuthResolver implements the standard token handshake described at
+// https://docs.docker.com/registry/spec/auth/token/: it parses the
+// "Bearer realm=\"...\",service=\"...\",scope=\"...\"" challenge and asks
+// Source for a token to present as "Authorization: Bearer <token>".
+type BearerAuthResolver struct {
+	Source TokenSource
+}
+
+// Authorize implements AuthResolver.
+func (r *BearerAuthResolver) Authorize(ctx context.Context, challenge string) (string, error) {
+	realm, service, scope, err := parseBearerChallenge(challenge)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := r.Source.Token(ctx, realm, service, scope)
+	if err != nil {
+		return "", err
+	}
+
+	return "Bearer " + token, nil
+}
+
+var bearerParamPattern = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// parseBearerChallenge extracts realm, service, and scope from a
+// WWW-Authenticate header of the form:
+//
+//	Bearer realm="https://auth.example.com/token",service="registry.example.com",scope="repository:foo/bar:pull"
+func parseBearerChallenge(challenge string) (realm, service, scope string, err error) {
+	if !strings.HasPrefix(challenge, "Bearer ") {
+		return "", "", "", fmt.Errorf("unsupported WWW-Authenticate challenge: %q", challenge)
+	}
+
+	params := make(map[string]string)
+	for _, match := range bearerParamPattern.FindAllStringSubmatch(challenge, -1) {
+		params[match[1]] = match[2]
+	}
+
+	realm, ok := params["realm"]
+	if !ok {
+		return "", "", "", fmt.Errorf("WWW-Authenticate challenge missing realm: %q", challenge)
+	}
+
+	return realm, params["service"], params["scope"], nil
+}
+
+// GCPServiceAccountTokenSource supplies a GCP OAuth2 access token obtained by
+// the caller, which is used as-is as the bearer token for registries (GCR,
+// AR) that accept GCP access tokens directly. It does not itself exchange
+// service account credentials for a token.
+type GCPServiceAccountTokenSource struct {
+	// AccessToken is refreshed by the caller (e.g. from
+	// golang.org/x/oauth2/google) and passed straight through; this type
+	// exists to make the promoter's auth-selection logic uniform across
+	// backends rather than to manage token refresh itself.
+	AccessToken string
+}
+
+// Token implements TokenSource.
+func (s *GCPServiceAccountTokenSource) Token(ctx context.Context, realm, service, scope string) (string, error) {
+	if s.AccessToken == "" {
+		return "", fmt.Errorf("no GCP access token available")
+	}
+	return s.AccessToken, nil
+}
+
+// RegistryTokenSource implements the generic token handshake described at
+// https://docs.docker.com/registry/spec/auth/token/#requesting-a-token: it
+// issues a GET to realm with service and scope as query parameters
+// (optionally presenting Username/Password as HTTP Basic credentials), and
+// returns the "token" (or, per the older spec variant, "access_token")
+// field of the JSON response. This is what lets registries with their own
+// token endpoint --- Harbor, Quay, ACR, GHCR, self-hosted distribution ---
+// authenticate, as opposed to GCPServiceAccountTokenSource, which is
+// GCR/AR-specific.
+type RegistryTokenSource struct {
+	Username string
+	Password string
+	// HTTPClient defaults to http.DefaultClient if nil.
+	HTTPClient *http.Client
+}
+
+func (s *RegistryTokenSource) httpClient() *http.Client {
+	if s.HTTPClient != nil {
+		return s.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// Token implements TokenSource.
+func (s *RegistryTokenSource) Token(ctx context.Context, realm, service, scope string) (string, error) {
+	endpoint, err := url.Parse(realm)
+	if err != nil {
+		return "", fmt.Errorf("invalid token realm %q: %v", realm, err)
+	}
+
+	q := endpoint.Query()
+	if service != "" {
+		q.Set("service", service)
+	}
+	if scope != "" {
+		q.Set("scope", scope)
+	}
+	endpoint.RawQuery = q.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	req = req.WithContext(ctx)
+	if s.Username != "" {
+		req.SetBasicAuth(s.Username, s.Password)
+	}
+
+	resp, err := s.httpClient().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("requesting token from %q: %v", endpoint.String(), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return "", fmt.Errorf("token endpoint %q returned %s: %s", endpoint.String(), resp.Status, body)
+	}
+
+	var parsed struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return "", fmt.Errorf("decoding token response from %q: %v", endpoint.String(), err)
+	}
+
+	switch {
+	case parsed.Token != "":
+		return parsed.Token, nil
+	case parsed.AccessToken != "":
+		return parsed.AccessToken, nil
+	default:
+		return "", fmt.Errorf("token endpoint %q returned no token", endpoint.String())
+	}
+}
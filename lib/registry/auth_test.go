@@ -0,0 +1,78 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeResolver always authorizes with a fixed value, ignoring the challenge.
+type fakeResolver struct{}
+
+func (fakeResolver) Authorize(ctx context.Context, challenge string) (string, error) {
+	return "Bearer faketoken", nil
+}
+
+func TestAuthTransportRewindsBodyOnRetry(t *testing.T) {
+	var attempt int
+	var bodies []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		attempt++
+		if attempt == 1 {
+			w.Header().Set("WWW-Authenticate",
+				`Bearer realm="https://auth.example.com/token",service="example.com"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	client := &http.Client{
+		Transport: &authTransport{host: "example.com", resolver: fakeResolver{}},
+	}
+
+	const manifestBody = "manifest-bytes"
+	req, err := http.NewRequest(http.MethodPut, server.URL, bytes.NewReader([]byte(manifestBody)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("client.Do() = _, %v, want no error", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("resp.StatusCode = %d, want %d", resp.StatusCode, http.StatusCreated)
+	}
+	if attempt != 2 {
+		t.Fatalf("server saw %d request(s), want 2 (unauthenticated probe + authenticated retry)", attempt)
+	}
+	if bodies[1] != manifestBody {
+		t.Errorf("retry body = %q, want %q: the body must be rewound, not drained", bodies[1], manifestBody)
+	}
+}
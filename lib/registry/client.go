@@ -0,0 +1,218 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package registry implements a client for the Docker Registry HTTP API V2
+// (https://docs.docker.com/registry/spec/api/), so that promotion can
+// operate against any OCI-conformant registry instead of shelling out to
+// "gcloud container images ...".
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/GoogleCloudPlatform/k8s-container-image-promoter/lib/dockerregistry"
+)
+
+// ManifestListMediaType and ManifestMediaType are the two manifest content
+// types that promotion cares about: a single-platform image manifest, and a
+// multi-platform manifest list ("fat manifest").
+const (
+	ManifestMediaType     = "application/vnd.docker.distribution.manifest.v2+json"
+	ManifestListMediaType = "application/vnd.docker.distribution.manifest.list.v2+json"
+)
+
+// Manifest is the raw bytes of a manifest or manifest list, together with
+// the content type that was used to fetch (or that should be used to store)
+// it. RegistryClient deliberately does not unmarshal the manifest body: the
+// promoter only ever needs to copy it verbatim between registries.
+type Manifest struct {
+	MediaType string
+	Digest    string
+	Bytes     []byte
+}
+
+// RegistryClient is the set of Registry HTTP API V2 operations the promoter
+// needs. A Client (below) is the production implementation; tests may
+// substitute a fake.
+type RegistryClient interface {
+	// ListTags lists all tags for the named repository
+	// (GET /v2/<name>/tags/list).
+	ListTags(ctx context.Context, name string) ([]string, error)
+	// GetManifest fetches a manifest or manifest list by tag or digest
+	// (GET /v2/<name>/manifests/<ref>).
+	GetManifest(ctx context.Context, name, ref string) (*Manifest, error)
+	// PutManifest uploads a manifest under the given tag or digest
+	// (PUT /v2/<name>/manifests/<ref>).
+	PutManifest(ctx context.Context, name, ref string, manifest *Manifest) error
+	// DeleteTag removes a tag or digest from the repository
+	// (DELETE /v2/<name>/manifests/<ref>).
+	DeleteTag(ctx context.Context, name, ref string) error
+}
+
+// Client is the default RegistryClient, talking to a single registry host
+// over HTTPS.
+type Client struct {
+	// Host is the registry's domain (and optional port), e.g.
+	// "us.gcr.io" or "registry.example.com:5000".
+	Host string
+	// HTTPClient is used to make all requests. Its Transport is expected to
+	// be an authTransport (see auth.go) so that requests are authenticated
+	// on demand.
+	HTTPClient *http.Client
+}
+
+// NameAndRef splits a parsed inventory.Reference into the "name" and "ref"
+// path segments used by the Registry HTTP API V2
+// (GET/PUT /v2/<name>/manifests/<ref>), preferring the digest over the tag
+// when both are present, since the digest is what's immutable.
+func NameAndRef(ref inventory.Reference) (name, reference string) {
+	reference = string(ref.Tag)
+	if ref.Digest != "" {
+		reference = string(ref.Digest)
+	}
+	return string(ref.Image), reference
+}
+
+// NewClient creates a Client for the registry at host, authenticating
+// requests with resolver.
+func NewClient(host string, resolver AuthResolver) *Client {
+	return &Client{
+		Host: host,
+		HTTPClient: &http.Client{
+			Transport: &authTransport{
+				host:     host,
+				resolver: resolver,
+			},
+		},
+	}
+}
+
+// ListTags implements RegistryClient.
+func (c *Client) ListTags(ctx context.Context, name string) ([]string, error) {
+	url := fmt.Sprintf("https://%s/v2/%s/tags/list", c.Host, name)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, unexpectedStatus(resp)
+	}
+
+	var parsed struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("decoding tags list for %q: %v", name, err)
+	}
+
+	return parsed.Tags, nil
+}
+
+// GetManifest implements RegistryClient.
+func (c *Client) GetManifest(ctx context.Context, name, ref string) (*Manifest, error) {
+	url := fmt.Sprintf("https://%s/v2/%s/manifests/%s", c.Host, name, ref)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", ManifestMediaType+", "+ManifestListMediaType)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, unexpectedStatus(resp)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading manifest for %q@%q: %v", name, ref, err)
+	}
+
+	return &Manifest{
+		MediaType: resp.Header.Get("Content-Type"),
+		Digest:    resp.Header.Get("Docker-Content-Digest"),
+		Bytes:     body,
+	}, nil
+}
+
+// PutManifest implements RegistryClient. It is used both to write a new tag
+// pointing at an existing manifest (a "Move" or "Add" TagOp) and to copy a
+// manifest to a new registry; in both cases the caller supplies the exact
+// bytes previously returned by GetManifest.
+func (c *Client) PutManifest(ctx context.Context, name, ref string, manifest *Manifest) error {
+	url := fmt.Sprintf("https://%s/v2/%s/manifests/%s", c.Host, name, ref)
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(manifest.Bytes))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", manifest.MediaType)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return unexpectedStatus(resp)
+	}
+	return nil
+}
+
+// DeleteTag implements RegistryClient.
+func (c *Client) DeleteTag(ctx context.Context, name, ref string) error {
+	url := fmt.Sprintf("https://%s/v2/%s/manifests/%s", c.Host, name, ref)
+	req, err := http.NewRequest(http.MethodDelete, url, nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		return unexpectedStatus(resp)
+	}
+	return nil
+}
+
+func unexpectedStatus(resp *http.Response) error {
+	body, _ := ioutil.ReadAll(resp.Body)
+	return fmt.Errorf("unexpected status %s from %s: %s", resp.Status, resp.Request.URL, body)
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/k8s-container-image-promoter/lib/dockerregistry"
+)
+
+// GCloudReader is the legacy, gcloud-shelling-out way of reading a
+// registry's digest/tag inventory for a single image. It is implemented
+// outside this package (it predates it); ReadDigestsAndTags takes it as a
+// parameter so that this package doesn't need to depend on gcloud at all.
+type GCloudReader func(
+	ctx context.Context, registryName inventory.RegistryName, imageName inventory.ImageName,
+) (inventory.DigestTags, error)
+
+// ReadDigestsAndTags reads the digest/tag inventory for imageName in
+// registryName, dispatching to the HTTP backend (via clientFor) or to
+// gcloudRead depending on what inventory.BackendForRegistry says registryName
+// is configured to use.
+func ReadDigestsAndTags(
+	ctx context.Context,
+	registryName inventory.RegistryName,
+	imageName inventory.ImageName,
+	backends map[inventory.RegistryName]inventory.RegistryBackend,
+	clientFor func(inventory.RegistryName) RegistryClient,
+	gcloudRead GCloudReader) (inventory.DigestTags, error) {
+
+	switch inventory.BackendForRegistry(registryName, backends) {
+	case inventory.HTTPBackend:
+		client := clientFor(registryName)
+		if client == nil {
+			return nil, fmt.Errorf("no RegistryClient configured for registry %q", registryName)
+		}
+		return readDigestsAndTagsHTTP(ctx, client, string(imageName))
+	default:
+		if gcloudRead == nil {
+			return nil, fmt.Errorf(
+				"registry %q is configured for the gcloud backend, but no GCloudReader was given",
+				registryName)
+		}
+		return gcloudRead(ctx, registryName, imageName)
+	}
+}
+
+// readDigestsAndTagsHTTP lists every tag of name and fetches each one's
+// manifest, to build the DigestTags view ReadDigestsAndTags returns.
+func readDigestsAndTagsHTTP(
+	ctx context.Context, client RegistryClient, name string) (inventory.DigestTags, error) {
+
+	tags, err := client.ListTags(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("listing tags for %q: %v", name, err)
+	}
+
+	digestTags := make(inventory.DigestTags)
+	for _, tag := range tags {
+		manifest, err := client.GetManifest(ctx, name, tag)
+		if err != nil {
+			return nil, fmt.Errorf("getting manifest for %s:%s: %v", name, tag, err)
+		}
+		digest := inventory.Digest(manifest.Digest)
+		digestTags[digest] = append(digestTags[digest], inventory.Tag(tag))
+	}
+
+	return digestTags, nil
+}
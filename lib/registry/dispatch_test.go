@@ -0,0 +1,101 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/k8s-container-image-promoter/lib/dockerregistry"
+)
+
+// fakeClient is a RegistryClient that serves a fixed set of tags, each
+// pointing at a manifest whose Docker-Content-Digest is the tag itself
+// prefixed with "sha256:".
+type fakeClient struct {
+	tags []string
+}
+
+func (c *fakeClient) ListTags(ctx context.Context, name string) ([]string, error) {
+	return c.tags, nil
+}
+
+func (c *fakeClient) GetManifest(ctx context.Context, name, ref string) (*Manifest, error) {
+	return &Manifest{Digest: "sha256:" + ref}, nil
+}
+
+func (c *fakeClient) PutManifest(ctx context.Context, name, ref string, manifest *Manifest) error {
+	return nil
+}
+
+func (c *fakeClient) DeleteTag(ctx context.Context, name, ref string) error {
+	return nil
+}
+
+func TestReadDigestsAndTagsHTTPBackend(t *testing.T) {
+	backends := map[inventory.RegistryName]inventory.RegistryBackend{
+		"example.com": inventory.HTTPBackend,
+	}
+	client := &fakeClient{tags: []string{"v1", "v2"}}
+
+	got, err := ReadDigestsAndTags(
+		context.Background(), "example.com", "foo", backends,
+		func(inventory.RegistryName) RegistryClient { return client }, nil)
+	if err != nil {
+		t.Fatalf("ReadDigestsAndTags() = _, %v, want no error", err)
+	}
+
+	want := inventory.DigestTags{
+		"sha256:v1": {"v1"},
+		"sha256:v2": {"v2"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ReadDigestsAndTags() = %v, want %v", got, want)
+	}
+	for digest, tags := range want {
+		if len(got[digest]) != len(tags) || got[digest][0] != tags[0] {
+			t.Errorf("got[%q] = %v, want %v", digest, got[digest], tags)
+		}
+	}
+}
+
+func TestReadDigestsAndTagsGCloudBackend(t *testing.T) {
+	backends := map[inventory.RegistryName]inventory.RegistryBackend{
+		"gcr.io/foo": inventory.GCloudBackend,
+	}
+
+	called := false
+	gcloudRead := func(
+		ctx context.Context, registryName inventory.RegistryName, imageName inventory.ImageName,
+	) (inventory.DigestTags, error) {
+		called = true
+		return inventory.DigestTags{"sha256:aaa": {"v1"}}, nil
+	}
+
+	got, err := ReadDigestsAndTags(
+		context.Background(), "gcr.io/foo", "bar", backends,
+		func(inventory.RegistryName) RegistryClient { return nil }, gcloudRead)
+	if err != nil {
+		t.Fatalf("ReadDigestsAndTags() = _, %v, want no error", err)
+	}
+	if !called {
+		t.Error("gcloudRead was not called for a GCloudBackend registry")
+	}
+	if len(got) != 1 {
+		t.Errorf("ReadDigestsAndTags() = %v, want 1 entry", got)
+	}
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package scanner provides reference inventory.Scanner implementations.
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/k8s-container-image-promoter/lib/dockerregistry"
+)
+
+// ScriptRunnerScanner scans an image by starting a container from it on a
+// local Docker daemon, running a user-supplied script inside it, and
+// parsing the script's stdout as a JSON array of findings. It is meant for
+// ad hoc checks that don't warrant a dedicated scanner, e.g. "is nginx.conf
+// present" or "which packages are installed".
+type ScriptRunnerScanner struct {
+	// DockerBin is the path to the docker binary; "docker" on PATH if
+	// empty.
+	DockerBin string
+	// Script is the path to the script to run inside the container. It is
+	// piped to the container's shell on stdin, so it never needs to be
+	// baked into the image being scanned.
+	Script string
+}
+
+// scriptFinding is the JSON shape a ScriptRunnerScanner's script is
+// expected to print to stdout, as an array.
+type scriptFinding struct {
+	Severity    string            `json:"severity"`
+	Description string            `json:"description"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// Scan implements inventory.Scanner.
+func (s *ScriptRunnerScanner) Scan(
+	ctx context.Context,
+	registry inventory.RegistryName,
+	image inventory.ImageName,
+	digest inventory.Digest) (inventory.ScanResult, error) {
+
+	ref := fmt.Sprintf("%s/%s@%s", registry, image, digest)
+
+	script, err := ioutil.ReadFile(s.Script)
+	if err != nil {
+		return inventory.ScanResult{}, fmt.Errorf("reading scan script %q: %v", s.Script, err)
+	}
+
+	dockerBin := s.DockerBin
+	if dockerBin == "" {
+		dockerBin = "docker"
+	}
+
+	cmd := exec.CommandContext(ctx, dockerBin, "run", "--rm", "-i", "--entrypoint", "sh", ref, "-s")
+	cmd.Stdin = bytes.NewReader(script)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return inventory.ScanResult{}, fmt.Errorf(
+			"running scan script against %q: %v: %s", ref, err, stderr.String())
+	}
+
+	var raw []scriptFinding
+	if err := json.Unmarshal(stdout.Bytes(), &raw); err != nil {
+		return inventory.ScanResult{}, fmt.Errorf(
+			"parsing findings from scan script against %q: %v", ref, err)
+	}
+
+	return toScanResult(raw), nil
+}
+
+func toScanResult(raw []scriptFinding) inventory.ScanResult {
+	var result inventory.ScanResult
+	for _, f := range raw {
+		finding := inventory.Finding{
+			Severity:    inventory.Severity(strings.ToUpper(f.Severity)),
+			Description: f.Description,
+			Metadata:    f.Metadata,
+		}
+		result.Findings = append(result.Findings, finding)
+		result.Severity = inventory.HigherSeverity(result.Severity, finding.Severity)
+	}
+	return result
+}
@@ -0,0 +1,43 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scanner
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/k8s-container-image-promoter/lib/dockerregistry"
+)
+
+func TestToScanResultSeverityAggregation(t *testing.T) {
+	// None of these findings is CRITICAL; a prior bug folded them down to
+	// "" anyway, which silently defeated any non-critical scan gate.
+	raw := []scriptFinding{
+		{Severity: "HIGH", Description: "a"},
+		{Severity: "LOW", Description: "b"},
+		{Severity: "MEDIUM", Description: "c"},
+	}
+
+	result := toScanResult(raw)
+
+	if result.Severity != inventory.SeverityHigh {
+		t.Fatalf("toScanResult(...).Severity = %q, want %q", result.Severity, inventory.SeverityHigh)
+	}
+
+	if !result.Severity.AtLeast(inventory.SeverityHigh) {
+		t.Error("result.Severity.AtLeast(SeverityHigh) = false, want true: a HIGH finding must block at a HIGH threshold")
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/k8s-container-image-promoter/lib/dockerregistry"
+)
+
+// TrivyScanner scans an image for known CVEs using the trivy CLI
+// (https://github.com/aquasecurity/trivy), wrapping
+// "trivy image --format json <ref>".
+type TrivyScanner struct {
+	// TrivyBin is the path to the trivy binary; "trivy" on PATH if empty.
+	TrivyBin string
+}
+
+// trivyReport is the subset of "trivy image --format json" output that
+// TrivyScanner cares about.
+type trivyReport struct {
+	Results []struct {
+		Vulnerabilities []struct {
+			VulnerabilityID string `json:"VulnerabilityID"`
+			PkgName         string `json:"PkgName"`
+			Severity        string `json:"Severity"`
+		} `json:"Vulnerabilities"`
+	} `json:"Results"`
+}
+
+// Scan implements inventory.Scanner.
+func (s *TrivyScanner) Scan(
+	ctx context.Context,
+	registry inventory.RegistryName,
+	image inventory.ImageName,
+	digest inventory.Digest) (inventory.ScanResult, error) {
+
+	ref := fmt.Sprintf("%s/%s@%s", registry, image, digest)
+
+	trivyBin := s.TrivyBin
+	if trivyBin == "" {
+		trivyBin = "trivy"
+	}
+
+	cmd := exec.CommandContext(ctx, trivyBin, "image", "--format", "json", ref)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return inventory.ScanResult{}, fmt.Errorf(
+			"running trivy against %q: %v: %s", ref, err, stderr.String())
+	}
+
+	var report trivyReport
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		return inventory.ScanResult{}, fmt.Errorf("parsing trivy output for %q: %v", ref, err)
+	}
+
+	var result inventory.ScanResult
+	for _, r := range report.Results {
+		for _, v := range r.Vulnerabilities {
+			finding := inventory.Finding{
+				Severity:    inventory.Severity(strings.ToUpper(v.Severity)),
+				Description: fmt.Sprintf("%s in package %s", v.VulnerabilityID, v.PkgName),
+				Metadata: map[string]string{
+					"vulnerability_id": v.VulnerabilityID,
+					"package":          v.PkgName,
+				},
+			}
+			result.Findings = append(result.Findings, finding)
+			result.Severity = inventory.HigherSeverity(result.Severity, finding.Severity)
+		}
+	}
+
+	return result, nil
+}